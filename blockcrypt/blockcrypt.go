@@ -0,0 +1,101 @@
+// Package blockcrypt gives Ginga a drop-in slot in UDP/KCP-style
+// transports that select their block cipher by configuration string
+// (the way kcp-go lets callers pick among AES, Twofish, CAST5, Salsa20,
+// SM4, XTEA, ...). Each BlockCrypt operates on a whole packet whose
+// first BlockSize bytes are a caller-supplied nonce/IV; the remaining
+// bytes are CTR-encrypted under Ginga keyed by a PBKDF2-derived key, so
+// callers never have to hand-roll the nonce-prefix framing themselves.
+package blockcrypt
+
+import (
+	"crypto/cipher"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"sync"
+
+	ginga "github.com/pedroalbanese/whirlx"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// pbkdf2Iterations and pbkdf2KeyLen match the parameters kcp-go itself
+// uses to turn a passphrase and salt into a block cipher key.
+const (
+	pbkdf2Iterations = 4096
+	pbkdf2KeyLen     = 32
+)
+
+// BlockCrypt encrypts or decrypts a whole packet in place between dst
+// and src. Implementations are expected to treat the first BlockSize
+// bytes of src as a nonce/IV that is carried through to dst unencrypted.
+type BlockCrypt interface {
+	Encrypt(dst, src []byte)
+	Decrypt(dst, src []byte)
+}
+
+// ErrShortPacket is the value Encrypt and Decrypt panic with when a
+// packet is too small to hold the leading nonce/IV.
+var ErrShortPacket = errors.New("blockcrypt: packet shorter than the nonce/IV")
+
+type gingaBlockCrypt struct {
+	block cipher.Block
+}
+
+// NewGingaBlockCrypt derives a 256-bit Ginga key from pass and salt via
+// PBKDF2-HMAC-SHA1 and returns a BlockCrypt that uses it.
+func NewGingaBlockCrypt(pass, salt []byte) (BlockCrypt, error) {
+	key := pbkdf2.Key(pass, salt, pbkdf2Iterations, pbkdf2KeyLen, sha1.New)
+	block, err := ginga.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &gingaBlockCrypt{block: block}, nil
+}
+
+func (c *gingaBlockCrypt) Encrypt(dst, src []byte) { c.crypt(dst, src) }
+func (c *gingaBlockCrypt) Decrypt(dst, src []byte) { c.crypt(dst, src) }
+
+// crypt carries the leading nonce/IV through unencrypted and
+// CTR-encrypts the rest under it. CTR's keystream XOR is its own
+// inverse, so the same code path serves both Encrypt and Decrypt.
+func (c *gingaBlockCrypt) crypt(dst, src []byte) {
+	if len(src) < ginga.BlockSize {
+		panic(ErrShortPacket)
+	}
+	copy(dst[:ginga.BlockSize], src[:ginga.BlockSize])
+	stream := cipher.NewCTR(c.block, src[:ginga.BlockSize])
+	stream.XORKeyStream(dst[ginga.BlockSize:], src[ginga.BlockSize:])
+}
+
+// registry lets applications select a cipher.Block constructor by name,
+// the way kcp-go's configuration selects among "aes", "twofish", "sm4",
+// ... A blank import or an explicit Register call is enough to make a
+// cipher available; this package registers "ginga" for itself.
+var registry = struct {
+	mu sync.RWMutex
+	m  map[string]func(key []byte) (cipher.Block, error)
+}{m: make(map[string]func(key []byte) (cipher.Block, error))}
+
+// Register makes a cipher.Block constructor available under name for
+// later lookup via ByName.
+func Register(name string, ctor func(key []byte) (cipher.Block, error)) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.m[name] = ctor
+}
+
+// ByName constructs the cipher.Block registered under name, keyed by
+// key.
+func ByName(name string, key []byte) (cipher.Block, error) {
+	registry.mu.RLock()
+	ctor, ok := registry.m[name]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("blockcrypt: unknown cipher %q", name)
+	}
+	return ctor(key)
+}
+
+func init() {
+	Register("ginga", ginga.NewCipher)
+}
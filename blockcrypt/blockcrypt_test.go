@@ -0,0 +1,127 @@
+package blockcrypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"testing"
+
+	ginga "github.com/pedroalbanese/whirlx"
+)
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	bc, err := NewGingaBlockCrypt([]byte("correct horse battery staple"), []byte("salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := make([]byte, ginga.BlockSize+100)
+	if _, err := rand.Read(plain[ginga.BlockSize:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rand.Read(plain[:ginga.BlockSize]); err != nil { // nonce/IV
+		t.Fatal(err)
+	}
+
+	ct := make([]byte, len(plain))
+	bc.Encrypt(ct, plain)
+
+	if !bytes.Equal(ct[:ginga.BlockSize], plain[:ginga.BlockSize]) {
+		t.Fatal("nonce/IV prefix must pass through unencrypted")
+	}
+
+	pt := make([]byte, len(plain))
+	bc.Decrypt(pt, ct)
+
+	if !bytes.Equal(pt, plain) {
+		t.Fatal("round trip mismatch")
+	}
+}
+
+func TestDifferentPassOrSaltDiffersCiphertext(t *testing.T) {
+	plain := make([]byte, ginga.BlockSize+32)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatal(err)
+	}
+
+	bc1, err := NewGingaBlockCrypt([]byte("pass1"), []byte("salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc2, err := NewGingaBlockCrypt([]byte("pass2"), []byte("salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ct1 := make([]byte, len(plain))
+	ct2 := make([]byte, len(plain))
+	bc1.Encrypt(ct1, plain)
+	bc2.Encrypt(ct2, plain)
+
+	if bytes.Equal(ct1[ginga.BlockSize:], ct2[ginga.BlockSize:]) {
+		t.Fatal("different passphrases must derive different keys")
+	}
+
+	bc3, err := NewGingaBlockCrypt([]byte("pass1"), []byte("salt2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct3 := make([]byte, len(plain))
+	bc3.Encrypt(ct3, plain)
+
+	if bytes.Equal(ct1[ginga.BlockSize:], ct3[ginga.BlockSize:]) {
+		t.Fatal("different salts must derive different keys")
+	}
+}
+
+func TestShortPacketPanics(t *testing.T) {
+	bc, err := NewGingaBlockCrypt([]byte("pass"), []byte("salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for packet shorter than the nonce/IV")
+		}
+	}()
+	short := make([]byte, ginga.BlockSize-1)
+	bc.Encrypt(short, short)
+}
+
+func TestRegistryByName(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ByName("ginga", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.BlockSize() != ginga.BlockSize {
+		t.Fatalf("unexpected block size %d", block.BlockSize())
+	}
+
+	if _, err := ByName("does-not-exist", key); err == nil {
+		t.Fatal("expected error for unregistered cipher name")
+	}
+}
+
+func TestRegisterCustomCipher(t *testing.T) {
+	var calledWith []byte
+	Register("test-alias", func(key []byte) (cipher.Block, error) {
+		calledWith = key
+		return ginga.NewCipher(key)
+	})
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ByName("test-alias", key); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(calledWith, key) {
+		t.Fatal("registered constructor did not receive the requested key")
+	}
+}
@@ -0,0 +1,159 @@
+// Package cmac implements CMAC, the NIST SP 800-38B cipher-based message
+// authentication code, specialized to 16-byte block ciphers such as
+// Ginga. CMAC fixes the security weaknesses of plain CBC-MAC on
+// variable-length messages by deriving two subkeys from the cipher
+// itself and mixing one of them into the final block before the last
+// encryption.
+package cmac
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// BlockSize is the block size this package requires of the underlying
+// cipher.Block.
+const BlockSize = 16
+
+// reductionPoly is the low byte XORed in when a subkey's left shift
+// carries a 1 bit out of the top, i.e. the minimal polynomial for
+// GF(2^128): x^128 + x^7 + x^2 + x + 1.
+const reductionPoly = 0x87
+
+var (
+	// ErrUnsupportedBlockSize is returned by New when given a
+	// cipher.Block whose block size is not 16 bytes.
+	ErrUnsupportedBlockSize = errors.New("cmac: cipher block size must be 16 bytes")
+	// ErrInvalidTagSize is returned by NewWithTagSize when the
+	// requested tag size is not between 1 and BlockSize.
+	ErrInvalidTagSize = errors.New("cmac: tag size must be between 1 and the cipher's block size")
+)
+
+// block is the subset of cipher.Block that CMAC needs.
+type block interface {
+	BlockSize() int
+	Encrypt(dst, src []byte)
+}
+
+// New returns a hash.Hash computing the full-size CMAC tag of whatever
+// is written to it, using c as the underlying block cipher.
+func New(c block) (hash.Hash, error) {
+	return NewWithTagSize(c, BlockSize)
+}
+
+// NewWithTagSize is like New but truncates Sum's output to tagSize
+// bytes, as permitted by NIST SP 800-38B.
+func NewWithTagSize(c block, tagSize int) (hash.Hash, error) {
+	if c.BlockSize() != BlockSize {
+		return nil, ErrUnsupportedBlockSize
+	}
+	if tagSize <= 0 || tagSize > BlockSize {
+		return nil, ErrInvalidTagSize
+	}
+
+	m := &cmac{cipher: c, tagSize: tagSize}
+
+	var l [BlockSize]byte
+	c.Encrypt(l[:], l[:])
+	m.k1 = shiftXor(l)
+	m.k2 = shiftXor(m.k1)
+
+	return m, nil
+}
+
+// Sum computes the CMAC tag of msg, truncated to tagSize bytes.
+func Sum(msg []byte, c block, tagSize int) ([]byte, error) {
+	h, err := NewWithTagSize(c, tagSize)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+// Verify reports whether mac is the valid CMAC tag of msg under c, in
+// constant time.
+func Verify(mac, msg []byte, c block, tagSize int) bool {
+	sum, err := Sum(msg, c, tagSize)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(mac, sum) == 1
+}
+
+// shiftXor left-shifts a 128-bit block by one bit and, if a 1 bit was
+// carried out of the top, XORs the reduction polynomial into the low
+// byte. This derives K1 from L and K2 from K1, per SP 800-38B.
+func shiftXor(in [BlockSize]byte) [BlockSize]byte {
+	var out [BlockSize]byte
+	var carry byte
+	for i := BlockSize - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+	if carry != 0 {
+		out[BlockSize-1] ^= reductionPoly
+	}
+	return out
+}
+
+// cmac implements hash.Hash, CBC-MAC chaining full blocks as they arrive
+// and holding back the final block (whether full or partial) until Sum
+// is called, since its treatment depends on whether it is the last one.
+type cmac struct {
+	cipher   block
+	k1, k2   [BlockSize]byte
+	buf      [BlockSize]byte
+	buffered int
+	tagSize  int
+}
+
+func (m *cmac) Size() int      { return m.tagSize }
+func (m *cmac) BlockSize() int { return BlockSize }
+
+func (m *cmac) Reset() {
+	m.buf = [BlockSize]byte{}
+	m.buffered = 0
+}
+
+func (m *cmac) Write(p []byte) (int, error) {
+	n := len(p)
+
+	// Encrypt a buffered block only once more input follows it, so the
+	// last block (full or partial) is always still in buf at Sum time
+	// and Sum can decide between K1 and K2/padding without having
+	// looked ahead past the end of the message.
+	for m.buffered+len(p) > BlockSize {
+		need := BlockSize - m.buffered
+		xorInto(m.buf[m.buffered:], p[:need])
+		m.cipher.Encrypt(m.buf[:], m.buf[:])
+		m.buffered = 0
+		p = p[need:]
+	}
+	xorInto(m.buf[m.buffered:m.buffered+len(p)], p)
+	m.buffered += len(p)
+
+	return n, nil
+}
+
+func (m *cmac) Sum(b []byte) []byte {
+	var last [BlockSize]byte
+	copy(last[:], m.buf[:])
+
+	if m.buffered == BlockSize {
+		xorInto(last[:], m.k1[:])
+	} else {
+		last[m.buffered] ^= 0x80
+		xorInto(last[:], m.k2[:])
+	}
+
+	m.cipher.Encrypt(last[:], last[:])
+	return append(b, last[:m.tagSize]...)
+}
+
+func xorInto(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
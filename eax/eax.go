@@ -0,0 +1,144 @@
+// Package eax implements the EAX authenticated-encryption mode (Bellare,
+// Rogaway and Wagner) on top of CMAC-Ginga and CTR-Ginga, exposed as a
+// crypto/cipher.AEAD.
+//
+// EAX authenticates a nonce, associated data and the ciphertext with
+// three independently domain-separated OMAC (CMAC) calls and combines
+// them by XOR into a single tag:
+//
+//	N' = OMAC_K(0 || nonce)
+//	H  = OMAC_K(1 || associatedData)
+//	C  = CTR_K,N'(plaintext)
+//	C' = OMAC_K(2 || C)
+//	tag = N' ^ H ^ C'
+//
+// Unlike GCM, EAX places no restriction on nonce length: any length
+// accepted by the underlying CMAC is valid, so NonceSize is advisory
+// only and Seal/Open do not reject other lengths.
+package eax
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/pedroalbanese/whirlx/cmac"
+)
+
+// defaultNonceSize is the size returned by NonceSize; it has no bearing
+// on what Seal/Open will accept.
+const defaultNonceSize = 16
+
+// TagSize is the full EAX tag size for a 16-byte block cipher.
+const TagSize = cmac.BlockSize
+
+var (
+	// ErrUnsupportedBlockSize is returned by New when given a
+	// cipher.Block whose block size is not 16 bytes.
+	ErrUnsupportedBlockSize = errors.New("eax: cipher block size must be 16 bytes")
+	// ErrInvalidTagSize is returned by NewWithTagSize when the
+	// requested tag size is not between 1 and TagSize.
+	ErrInvalidTagSize = errors.New("eax: tag size must be between 1 and the cipher's block size")
+	errOpen           = errors.New("eax: message authentication failed")
+)
+
+type eax struct {
+	block   cipher.Block
+	tagSize int
+}
+
+// New wraps block (typically from ginga.NewCipher) in EAX with a
+// full-size tag.
+func New(block cipher.Block) (cipher.AEAD, error) {
+	return NewWithTagSize(block, TagSize)
+}
+
+// NewWithTagSize is like New but truncates the authentication tag to
+// tagSize bytes.
+func NewWithTagSize(block cipher.Block, tagSize int) (cipher.AEAD, error) {
+	if block.BlockSize() != cmac.BlockSize {
+		return nil, ErrUnsupportedBlockSize
+	}
+	if tagSize <= 0 || tagSize > TagSize {
+		return nil, ErrInvalidTagSize
+	}
+	return &eax{block: block, tagSize: tagSize}, nil
+}
+
+func (e *eax) NonceSize() int { return defaultNonceSize }
+func (e *eax) Overhead() int  { return e.tagSize }
+
+// omac computes OMAC_K(t || msg), where the one-byte tweak t is encoded
+// as the last byte of a leading zero block, per the EAX construction.
+func (e *eax) omac(t byte, msg []byte) []byte {
+	h, err := cmac.New(e.block)
+	if err != nil {
+		// e.block's size was already validated in NewWithTagSize.
+		panic("eax: " + err.Error())
+	}
+	var prefix [cmac.BlockSize]byte
+	prefix[cmac.BlockSize-1] = t
+	h.Write(prefix[:])
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+func (e *eax) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	nPrime := e.omac(0, nonce)
+	h := e.omac(1, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+e.tagSize)
+	ciphertext := out[:len(plaintext)]
+
+	stream := cipher.NewCTR(e.block, nPrime)
+	stream.XORKeyStream(ciphertext, plaintext)
+
+	cPrime := e.omac(2, ciphertext)
+
+	tag := out[len(plaintext):]
+	for i := 0; i < e.tagSize; i++ {
+		tag[i] = nPrime[i] ^ h[i] ^ cPrime[i]
+	}
+
+	return ret
+}
+
+func (e *eax) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < e.tagSize {
+		return nil, errOpen
+	}
+	ct := ciphertext[:len(ciphertext)-e.tagSize]
+	tag := ciphertext[len(ciphertext)-e.tagSize:]
+
+	nPrime := e.omac(0, nonce)
+	h := e.omac(1, additionalData)
+	cPrime := e.omac(2, ct)
+
+	want := make([]byte, e.tagSize)
+	for i := 0; i < e.tagSize; i++ {
+		want[i] = nPrime[i] ^ h[i] ^ cPrime[i]
+	}
+	if subtle.ConstantTimeCompare(want, tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ct))
+	stream := cipher.NewCTR(e.block, nPrime)
+	stream.XORKeyStream(out, ct)
+
+	return ret, nil
+}
+
+// sliceForAppend extends in by n bytes, like crypto/cipher's AEAD
+// implementations do, returning both the extended slice and the tail
+// that was appended.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
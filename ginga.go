@@ -139,16 +139,67 @@ func Decrypt(ciphertext, key []byte) ([]byte, error) {
 
 // --- Integração com cipher.Block (NewCipher) ---
 
+// subkeys is the fully expanded, per-round per-lane key schedule: one
+// uint32 for each of the Rounds rounds and each of the 4 state lanes.
+type subkeys [Rounds][4]uint32
+
+// expandKey derives the complete subkey table once from the raw key
+// words, so that Encrypt/Decrypt (and the fast paths in ginga_modes.go)
+// never need to call subKey32 per block.
+func expandKey(k *[8]uint32) *subkeys {
+	var sk subkeys
+	for r := 0; r < Rounds; r++ {
+		for i := 0; i < 4; i++ {
+			sk[r][i] = subKey32(k, r, i)
+		}
+	}
+	return &sk
+}
+
+// encryptWords runs the full forward cipher on a 4-lane state in place
+// using a precomputed subkey table.
+func encryptWords(c *[4]uint32, sk *subkeys) {
+	for r := 0; r < Rounds; r++ {
+		for i := 0; i < 4; i++ {
+			c[i] = round32(c[i], sk[r][i], r)
+		}
+		mixState32(c)
+	}
+}
+
+// decryptWords runs the full inverse cipher on a 4-lane state in place
+// using a precomputed subkey table.
+func decryptWords(p *[4]uint32, sk *subkeys) {
+	for r := Rounds - 1; r >= 0; r-- {
+		invMixState32(p)
+		for i := 0; i < 4; i++ {
+			p[i] = invRound32(p[i], sk[r][i], r)
+		}
+	}
+}
+
 type gingaCipher struct {
-	key []byte
+	key     []byte
+	subkeys *subkeys
 }
 
-// NewCipher cria um objeto cipher.Block compatível com modos de operação
+// NewCipher cria um objeto cipher.Block compatível com modos de operação.
+// A chave é expandida uma única vez na tabela de subchaves por rodada,
+// que é então reaproveitada por todas as chamadas a Encrypt/Decrypt e
+// pelos modos de operação otimizados (NewCBCEncrypter, NewCBCDecrypter,
+// NewCTR).
 func NewCipher(key []byte) (cipher.Block, error) {
 	if len(key) != 32 {
 		return nil, errors.New("ginga: invalid key size (must be 32 bytes)")
 	}
-	return &gingaCipher{key: append([]byte(nil), key...)}, nil
+	var k [8]uint32
+	for i := 0; i < 8; i++ {
+		k[i] = binary.LittleEndian.Uint32(key[i*4 : (i+1)*4])
+	}
+	return &gingaCipher{
+		key:     append([]byte(nil), key...),
+		subkeys: expandKey(&k),
+	}, nil
 }
 
 // BlockSize retorna o tamanho do bloco da cifra (16 bytes)
@@ -156,26 +207,34 @@ func (c *gingaCipher) BlockSize() int {
 	return BlockSize
 }
 
-// Encrypt cifra exatamente um bloco de 16 bytes
+// Encrypt cifra exatamente um bloco de 16 bytes usando a tabela de
+// subchaves pré-computada, sem recalculá-la a cada chamada.
 func (c *gingaCipher) Encrypt(dst, src []byte) {
 	if len(src) < BlockSize || len(dst) < BlockSize {
 		panic("ginga: input not full block")
 	}
-	out, err := Encrypt(src[:BlockSize], c.key)
-	if err != nil {
-		panic("ginga: encryption failed: " + err.Error())
+	var state [4]uint32
+	for i := 0; i < 4; i++ {
+		state[i] = binary.LittleEndian.Uint32(src[i*4 : (i+1)*4])
+	}
+	encryptWords(&state, c.subkeys)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint32(dst[i*4:(i+1)*4], state[i])
 	}
-	copy(dst, out)
 }
 
-// Decrypt decifra exatamente um bloco de 16 bytes
+// Decrypt decifra exatamente um bloco de 16 bytes usando a tabela de
+// subchaves pré-computada, sem recalculá-la a cada chamada.
 func (c *gingaCipher) Decrypt(dst, src []byte) {
 	if len(src) < BlockSize || len(dst) < BlockSize {
 		panic("ginga: input not full block")
 	}
-	out, err := Decrypt(src[:BlockSize], c.key)
-	if err != nil {
-		panic("ginga: decryption failed: " + err.Error())
+	var state [4]uint32
+	for i := 0; i < 4; i++ {
+		state[i] = binary.LittleEndian.Uint32(src[i*4 : (i+1)*4])
+	}
+	decryptWords(&state, c.subkeys)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint32(dst[i*4:(i+1)*4], state[i])
 	}
-	copy(dst, out)
 }
@@ -0,0 +1,336 @@
+package ginga
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+// cbcEncAble, cbcDecAble and ctrAble mirror the unexported interfaces
+// crypto/aes uses to let crypto/cipher's NewCBCEncrypter, NewCBCDecrypter
+// and NewCTR pick up a cipher-specific fast path instead of the generic
+// byte-slice-oriented implementation.
+
+type cbcEncAble interface {
+	NewCBCEncrypter(iv []byte) cipher.BlockMode
+}
+
+type cbcDecAble interface {
+	NewCBCDecrypter(iv []byte) cipher.BlockMode
+}
+
+type ctrAble interface {
+	NewCTR(iv []byte) cipher.Stream
+}
+
+// gingaCBCEncrypter and gingaCBCDecrypter operate on the [4]uint32 state
+// directly, avoiding the load/store round trip through gingaCipher's
+// byte-slice Encrypt/Decrypt wrappers for every block.
+
+type gingaCBCEncrypter struct {
+	subkeys *subkeys
+	iv      [4]uint32
+}
+
+// NewCBCEncrypter returns a cipher.BlockMode that encrypts in CBC mode
+// using the cipher's precomputed subkey table. It is picked up
+// automatically by cipher.NewCBCEncrypter via the cbcEncAble interface.
+//
+// Unlike the CTR fast path (encryptBlocksCTR), this is not a batched,
+// ILP-friendly implementation: CBC chains each block's input to the
+// previous block's output, so blocks cannot be processed side by side
+// regardless of how they're keyed. Skipping gingaCipher's byte-slice
+// Encrypt wrapper and its per-block load/store of the state only removes
+// that overhead, not the serial dependency chain, so
+// this fast path does not give CBC the order-of-magnitude throughput
+// gain CTR gets from batching.
+func (c *gingaCipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("ginga: IV length must equal block size")
+	}
+	m := &gingaCBCEncrypter{subkeys: c.subkeys}
+	for i := 0; i < 4; i++ {
+		m.iv[i] = binary.LittleEndian.Uint32(iv[i*4 : (i+1)*4])
+	}
+	return m
+}
+
+func (m *gingaCBCEncrypter) BlockSize() int { return BlockSize }
+
+func (m *gingaCBCEncrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("ginga: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("ginga: output smaller than input")
+	}
+	prev := m.iv
+	for len(src) > 0 {
+		var c [4]uint32
+		for i := 0; i < 4; i++ {
+			c[i] = binary.LittleEndian.Uint32(src[i*4:(i+1)*4]) ^ prev[i]
+		}
+		encryptWords(&c, m.subkeys)
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint32(dst[i*4:(i+1)*4], c[i])
+		}
+		prev = c
+		src = src[BlockSize:]
+		dst = dst[BlockSize:]
+	}
+	m.iv = prev
+}
+
+type gingaCBCDecrypter struct {
+	subkeys *subkeys
+	iv      [4]uint32
+}
+
+// NewCBCDecrypter returns a cipher.BlockMode that decrypts in CBC mode
+// using the cipher's precomputed subkey table. It is picked up
+// automatically by cipher.NewCBCDecrypter via the cbcDecAble interface.
+//
+// CBC decryption can batch across blocks in principle, since each
+// block's decryption only depends on ciphertext, but this implementation
+// does not: it exists to avoid gingaCipher's byte-slice wrapper and its
+// per-block load/store of the state, the same narrow win as
+// NewCBCEncrypter, not a throughput-oriented rewrite like CTR's
+// encryptBlocksCTR.
+func (c *gingaCipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("ginga: IV length must equal block size")
+	}
+	m := &gingaCBCDecrypter{subkeys: c.subkeys}
+	for i := 0; i < 4; i++ {
+		m.iv[i] = binary.LittleEndian.Uint32(iv[i*4 : (i+1)*4])
+	}
+	return m
+}
+
+func (m *gingaCBCDecrypter) BlockSize() int { return BlockSize }
+
+func (m *gingaCBCDecrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("ginga: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("ginga: output smaller than input")
+	}
+	prev := m.iv
+	for len(src) > 0 {
+		var ct [4]uint32
+		for i := 0; i < 4; i++ {
+			ct[i] = binary.LittleEndian.Uint32(src[i*4 : (i+1)*4])
+		}
+		p := ct
+		decryptWords(&p, m.subkeys)
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint32(dst[i*4:(i+1)*4], p[i]^prev[i])
+		}
+		prev = ct
+		src = src[BlockSize:]
+		dst = dst[BlockSize:]
+	}
+	m.iv = prev
+}
+
+// gingaCTR implements CTR mode directly over the [4]uint32 state,
+// batching through encryptBlocksCTR when enough blocks are requested at
+// once.
+
+type gingaCTR struct {
+	subkeys *subkeys
+	ctr     [BlockSize]byte
+	ks      [BlockSize]byte // keystream of the last, partly used block
+	left    int             // unused bytes at the end of ks
+}
+
+// NewCTR returns a cipher.Stream that encrypts/decrypts in CTR mode using
+// the cipher's precomputed subkey table. It is picked up automatically by
+// cipher.NewCTR via the ctrAble interface. The counter is incremented the
+// same way crypto/cipher's generic CTR does (last byte first), so the
+// fast path and the generic path produce identical keystreams for the
+// same IV.
+func (c *gingaCipher) NewCTR(iv []byte) cipher.Stream {
+	if len(iv) != BlockSize {
+		panic("ginga: IV length must equal block size")
+	}
+	s := &gingaCTR{subkeys: c.subkeys}
+	copy(s.ctr[:], iv)
+	return s
+}
+
+func (s *gingaCTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("ginga: output smaller than input")
+	}
+
+	if s.left > 0 {
+		n := s.left
+		if len(src) < n {
+			n = len(src)
+		}
+		off := BlockSize - s.left
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ s.ks[off+i]
+		}
+		s.left -= n
+		src = src[n:]
+		dst = dst[n:]
+	}
+
+	if len(src) >= ctrBatchBlocks*BlockSize {
+		n := encryptBlocksCTR(dst, src, &s.ctr, s.subkeys)
+		src = src[n:]
+		dst = dst[n:]
+	}
+
+	for len(src) > 0 {
+		var ks [4]uint32
+		for i := 0; i < 4; i++ {
+			ks[i] = binary.LittleEndian.Uint32(s.ctr[i*4 : (i+1)*4])
+		}
+		encryptWords(&ks, s.subkeys)
+		incCounter(&s.ctr)
+
+		n := BlockSize
+		if len(src) < n {
+			n = len(src)
+		}
+		var block [BlockSize]byte
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint32(block[i*4:(i+1)*4], ks[i])
+		}
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ block[i]
+		}
+		if n < BlockSize {
+			s.ks = block
+			s.left = BlockSize - n
+		}
+		src = src[n:]
+		dst = dst[n:]
+	}
+}
+
+// ctrBatchBlocks is the number of counter blocks encryptBlocksCTR
+// processes together. Running the round function over several
+// independent lanes side by side gives the Go compiler enough
+// independent work to schedule adds/XORs/rotates across blocks without
+// waiting on each other's dependency chains, which a single interleaved
+// block cannot offer on its own.
+//
+// The four lanes are held in plain scalar locals (c0_0..c3_3), not a
+// [ctrBatchBlocks][4]uint32 array: indexing that array with loop
+// variables, as a straightforward version would, forces the whole
+// state to spill to the stack every round instead of staying in
+// registers, which made the batched path measurably slower than the
+// single-block path it was meant to beat. Scalars let the compiler keep
+// all 16 words in registers across the 16-round loop.
+const ctrBatchBlocks = 4
+
+// encryptBlocksCTR encrypts one group of ctrBatchBlocks counter blocks
+// at a time for as long as src holds at least that many, XORing the
+// keystream into dst and advancing counter past the blocks it consumed.
+// It returns the number of bytes processed, always a multiple of
+// ctrBatchBlocks*BlockSize.
+func encryptBlocksCTR(dst, src []byte, counter *[BlockSize]byte, sk *subkeys) int {
+	processed := 0
+	for len(src) >= ctrBatchBlocks*BlockSize {
+		c0_0 := binary.LittleEndian.Uint32(counter[0:4])
+		c0_1 := binary.LittleEndian.Uint32(counter[4:8])
+		c0_2 := binary.LittleEndian.Uint32(counter[8:12])
+		c0_3 := binary.LittleEndian.Uint32(counter[12:16])
+		incCounter(counter)
+		c1_0 := binary.LittleEndian.Uint32(counter[0:4])
+		c1_1 := binary.LittleEndian.Uint32(counter[4:8])
+		c1_2 := binary.LittleEndian.Uint32(counter[8:12])
+		c1_3 := binary.LittleEndian.Uint32(counter[12:16])
+		incCounter(counter)
+		c2_0 := binary.LittleEndian.Uint32(counter[0:4])
+		c2_1 := binary.LittleEndian.Uint32(counter[4:8])
+		c2_2 := binary.LittleEndian.Uint32(counter[8:12])
+		c2_3 := binary.LittleEndian.Uint32(counter[12:16])
+		incCounter(counter)
+		c3_0 := binary.LittleEndian.Uint32(counter[0:4])
+		c3_1 := binary.LittleEndian.Uint32(counter[4:8])
+		c3_2 := binary.LittleEndian.Uint32(counter[8:12])
+		c3_3 := binary.LittleEndian.Uint32(counter[12:16])
+		incCounter(counter)
+
+		for r := 0; r < Rounds; r++ {
+			k0, k1, k2, k3 := sk[r][0], sk[r][1], sk[r][2], sk[r][3]
+
+			c0_0 = round32(c0_0, k0, r)
+			c1_0 = round32(c1_0, k0, r)
+			c2_0 = round32(c2_0, k0, r)
+			c3_0 = round32(c3_0, k0, r)
+
+			c0_1 = round32(c0_1, k1, r)
+			c1_1 = round32(c1_1, k1, r)
+			c2_1 = round32(c2_1, k1, r)
+			c3_1 = round32(c3_1, k1, r)
+
+			c0_2 = round32(c0_2, k2, r)
+			c1_2 = round32(c1_2, k2, r)
+			c2_2 = round32(c2_2, k2, r)
+			c3_2 = round32(c3_2, k2, r)
+
+			c0_3 = round32(c0_3, k3, r)
+			c1_3 = round32(c1_3, k3, r)
+			c2_3 = round32(c2_3, k3, r)
+			c3_3 = round32(c3_3, k3, r)
+
+			c0_0, c0_1, c0_2, c0_3 = mixStateScalar(c0_0, c0_1, c0_2, c0_3)
+			c1_0, c1_1, c1_2, c1_3 = mixStateScalar(c1_0, c1_1, c1_2, c1_3)
+			c2_0, c2_1, c2_2, c2_3 = mixStateScalar(c2_0, c2_1, c2_2, c2_3)
+			c3_0, c3_1, c3_2, c3_3 = mixStateScalar(c3_0, c3_1, c3_2, c3_3)
+		}
+
+		putCTRBlock(dst, src, 0*BlockSize, c0_0, c0_1, c0_2, c0_3)
+		putCTRBlock(dst, src, 1*BlockSize, c1_0, c1_1, c1_2, c1_3)
+		putCTRBlock(dst, src, 2*BlockSize, c2_0, c2_1, c2_2, c2_3)
+		putCTRBlock(dst, src, 3*BlockSize, c3_0, c3_1, c3_2, c3_3)
+
+		n := ctrBatchBlocks * BlockSize
+		src = src[n:]
+		dst = dst[n:]
+		processed += n
+	}
+	return processed
+}
+
+// mixStateScalar is mixState32 rewritten over four scalar lanes instead
+// of a *[4]uint32, so callers can keep a whole batch of states in plain
+// local variables.
+func mixStateScalar(s0, s1, s2, s3 uint32) (uint32, uint32, uint32, uint32) {
+	s0 ^= rotl32(s1, 5)
+	s1 ^= rotl32(s2, 11)
+	s2 ^= rotl32(s3, 17)
+	s3 ^= rotl32(s0, 23)
+	return s0, s1, s2, s3
+}
+
+// putCTRBlock XORs one 16-byte keystream block, given as four lanes,
+// into src at the given offset and writes the result to dst at the same
+// offset.
+func putCTRBlock(dst, src []byte, off int, s0, s1, s2, s3 uint32) {
+	var block [BlockSize]byte
+	binary.LittleEndian.PutUint32(block[0:4], s0)
+	binary.LittleEndian.PutUint32(block[4:8], s1)
+	binary.LittleEndian.PutUint32(block[8:12], s2)
+	binary.LittleEndian.PutUint32(block[12:16], s3)
+	for i := 0; i < BlockSize; i++ {
+		dst[off+i] = src[off+i] ^ block[i]
+	}
+}
+
+// incCounter increments the 128-bit big-endian counter in place, last
+// byte first, matching crypto/cipher's generic CTR implementation.
+func incCounter(ctr *[BlockSize]byte) {
+	for i := len(ctr) - 1; i >= 0; i-- {
+		ctr[i]++
+		if ctr[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package xts implements the XEX-based tweakable-codebook mode with
+// ciphertext stealing (XTS), as specified by IEEE P1619, on top of the
+// Ginga block cipher. XTS is the standard construction for sector/disk
+// encryption: it wraps the 16-byte Ginga block cipher with Rogaway's XEX
+// mode so that every sector is encrypted under a unique, sector-number
+// derived tweak, while ciphertext stealing lets the last sector block be
+// handled even when the sector length is not a multiple of the cipher's
+// block size.
+//
+// XTS provides no authentication. As with any disk-encryption mode, an
+// attacker who can write to the ciphertext can flip bits of the
+// corresponding plaintext block undetected.
+package xts
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	ginga "github.com/pedroalbanese/whirlx"
+)
+
+// blockSize is the block size Ginga operates on; XTS is only defined for
+// 16-byte ciphers.
+const blockSize = ginga.BlockSize
+
+// keySize is the length of the key accepted by NewCipher: two 32-byte
+// Ginga keys concatenated, one for the data cipher and one for the tweak
+// cipher.
+const keySize = 64
+
+// Valid sector sizes for Encrypt/Decrypt, matching the sizes used by
+// full-disk/volume encryption stacks (LUKS-style sector sizes).
+const (
+	SectorSize512  = 512
+	SectorSize1024 = 1024
+	SectorSize2048 = 2048
+	SectorSize4096 = 4096
+)
+
+var (
+	// ErrInvalidKeySize is returned by NewCipher when the key is not 64
+	// bytes long.
+	ErrInvalidKeySize = errors.New("xts: key must be 64 bytes (two 32-byte Ginga keys)")
+	// ErrInvalidSectorSize is returned by Encrypt/Decrypt when the
+	// sector is not one of the supported sizes.
+	ErrInvalidSectorSize = errors.New("xts: sector size must be 512, 1024, 2048 or 4096 bytes")
+	// ErrShortBuffer is returned by Encrypt/Decrypt when dst is smaller
+	// than src.
+	ErrShortBuffer = errors.New("xts: output smaller than input")
+)
+
+func validSectorSize(n int) bool {
+	switch n {
+	case SectorSize512, SectorSize1024, SectorSize2048, SectorSize4096:
+		return true
+	default:
+		return false
+	}
+}
+
+// Cipher is an expanded Ginga-XTS key pair: a data cipher that encrypts
+// each block and a tweak cipher that derives the per-block tweak from
+// the sector number.
+type Cipher struct {
+	dataCipher  cipher.Block
+	tweakCipher cipher.Block
+}
+
+// NewCipher creates an XTS-Ginga Cipher from a 64-byte key: the first 32
+// bytes key the data cipher and the last 32 bytes key the tweak cipher.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, ErrInvalidKeySize
+	}
+	dataCipher, err := ginga.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	tweakCipher, err := ginga.NewCipher(key[32:])
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{dataCipher: dataCipher, tweakCipher: tweakCipher}, nil
+}
+
+// initTweak derives the initial tweak for a sector by encrypting the
+// little-endian sector number under the tweak cipher.
+func (c *Cipher) initTweak(sectorNum uint64) [blockSize]byte {
+	var tweak [blockSize]byte
+	binary.LittleEndian.PutUint64(tweak[:8], sectorNum)
+	c.tweakCipher.Encrypt(tweak[:], tweak[:])
+	return tweak
+}
+
+// mulAlpha multiplies the tweak by alpha (the generator x) in GF(2^128),
+// as defined by IEEE P1619: shift left by one bit and, if a 1 bit was
+// carried out of the top, XOR the reduction polynomial 0x87 into the low
+// byte.
+func mulAlpha(tweak *[blockSize]byte) {
+	var carryIn byte
+	for i := range tweak {
+		carryOut := tweak[i] >> 7
+		tweak[i] = tweak[i]<<1 | carryIn
+		carryIn = carryOut
+	}
+	if carryIn != 0 {
+		tweak[0] ^= 0x87
+	}
+}
+
+func xorBlock(dst, a, b []byte) {
+	for i := 0; i < blockSize; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// Encrypt encrypts a whole sector of plaintext into ciphertext under the
+// given sector number. len(src) must be one of the supported sector
+// sizes; ciphertext stealing is applied automatically if that size is
+// not a multiple of the block size.
+func (c *Cipher) Encrypt(dst, src []byte, sectorNum uint64) error {
+	if !validSectorSize(len(src)) {
+		return ErrInvalidSectorSize
+	}
+	if len(dst) < len(src) {
+		return ErrShortBuffer
+	}
+	c.crypt(dst, src, sectorNum, true)
+	return nil
+}
+
+// Decrypt decrypts a whole sector of ciphertext into plaintext under the
+// given sector number. len(src) must be one of the supported sector
+// sizes.
+func (c *Cipher) Decrypt(dst, src []byte, sectorNum uint64) error {
+	if !validSectorSize(len(src)) {
+		return ErrInvalidSectorSize
+	}
+	if len(dst) < len(src) {
+		return ErrShortBuffer
+	}
+	c.crypt(dst, src, sectorNum, false)
+	return nil
+}
+
+// crypt implements Rogaway's XEX mode with ciphertext stealing, shared by
+// Encrypt and Decrypt. Each of the supported sector sizes happens to be
+// a multiple of blockSize, so the ciphertext-stealing branch only
+// matters for callers that exercise it directly against a non-standard
+// length; it is kept general rather than assuming a clean multiple.
+func (c *Cipher) crypt(dst, src []byte, sectorNum uint64, encrypt bool) {
+	n := len(src)
+	blocks := n / blockSize
+	rem := n % blockSize
+
+	if rem != 0 && n < blockSize {
+		// Ciphertext stealing merges the trailing partial block into
+		// the preceding full block; there must be at least one full
+		// block to steal from. Unreachable through Encrypt/Decrypt,
+		// since every supported sector size is a multiple of
+		// blockSize, but crypt is written to hold for any length.
+		panic("xts: sector shorter than one block cannot use ciphertext stealing")
+	}
+
+	tweak := c.initTweak(sectorNum)
+
+	full := blocks
+	if rem != 0 {
+		// The last full block is combined with the trailing partial
+		// block via ciphertext stealing, so process one fewer block
+		// in the main loop.
+		full--
+	}
+
+	for i := 0; i < full; i++ {
+		block := src[i*blockSize : (i+1)*blockSize]
+		out := dst[i*blockSize : (i+1)*blockSize]
+		xorBlock(out, block, tweak[:])
+		if encrypt {
+			c.dataCipher.Encrypt(out, out)
+		} else {
+			c.dataCipher.Decrypt(out, out)
+		}
+		xorBlock(out, out, tweak[:])
+		mulAlpha(&tweak)
+	}
+
+	if rem == 0 {
+		return
+	}
+
+	penultimate := src[full*blockSize : (full+1)*blockSize]
+	last := src[(full+1)*blockSize:]
+
+	tweakNext := tweak
+	mulAlpha(&tweakNext)
+
+	if encrypt {
+		var cc [blockSize]byte
+		xorBlock(cc[:], penultimate, tweak[:])
+		c.dataCipher.Encrypt(cc[:], cc[:])
+		xorBlock(cc[:], cc[:], tweak[:])
+
+		// cc[:rem] is the short final ciphertext block; cc[rem:] is
+		// stolen and merged with the trailing plaintext to form the
+		// full block that takes its place.
+		var pp [blockSize]byte
+		copy(pp[:rem], last)
+		copy(pp[rem:], cc[rem:])
+
+		stolen := dst[full*blockSize : full*blockSize+blockSize]
+		xorBlock(stolen, pp[:], tweakNext[:])
+		c.dataCipher.Encrypt(stolen, stolen)
+		xorBlock(stolen, stolen, tweakNext[:])
+
+		copy(dst[(full+1)*blockSize:], cc[:rem])
+	} else {
+		stolen := src[full*blockSize : full*blockSize+blockSize]
+		var pp [blockSize]byte
+		xorBlock(pp[:], stolen, tweakNext[:])
+		c.dataCipher.Decrypt(pp[:], pp[:])
+		xorBlock(pp[:], pp[:], tweakNext[:])
+
+		copy(dst[(full+1)*blockSize:], pp[:rem])
+
+		var cc [blockSize]byte
+		copy(cc[:rem], last)
+		copy(cc[rem:], pp[rem:])
+
+		penultimateOut := dst[full*blockSize : (full+1)*blockSize]
+		xorBlock(penultimateOut, cc[:], tweak[:])
+		c.dataCipher.Decrypt(penultimateOut, penultimateOut)
+		xorBlock(penultimateOut, penultimateOut, tweak[:])
+	}
+}